@@ -11,11 +11,42 @@ import (
 	"io"
 	"math"
 
-	"github.com/nielsAD/gowarcraft3/protocol"
+	"github.com/nielsAD/gowarcraft3/pkg/util"
 )
 
 const defaultBufSize = 8192
 
+// ZlibWriter is the subset of *zlib.Writer used by Compressor, allowing
+// CompressorOptions.NewZlibWriter to plug in an alternative implementation
+// (e.g. github.com/klauspost/compress/zlib) as a drop-in replacement
+type ZlibWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// CompressorOptions configures the zlib codec used by a Compressor
+type CompressorOptions struct {
+	Level int    // zlib compression level (e.g. zlib.BestCompression)
+	Dict  []byte // Optional preset zlib dictionary
+
+	// NewZlibWriter overrides the zlib.Writer implementation. Defaults to
+	// zlib.NewWriterLevelDict.
+	NewZlibWriter func(w io.Writer, level int, dict []byte) (ZlibWriter, error)
+}
+
+var defaultCompressorOptions = CompressorOptions{
+	Level: zlib.BestCompression,
+	NewZlibWriter: func(w io.Writer, level int, dict []byte) (ZlibWriter, error) {
+		return zlib.NewWriterLevelDict(w, level, dict)
+	},
+}
+
+// scratchPool supplies the per-block header/CRC scratch buffer used by
+// Compressor.Write, so concurrently-used Compressor instances share buffers
+// instead of each holding their own
+var scratchPool util.BufferPool
+
 // Compressor is an io.Writer that compresses data blocks
 type Compressor struct {
 	SizeWritten uint32 // Compressed size written in total
@@ -23,22 +54,39 @@ type Compressor struct {
 	NumBlocks   uint32 // Blocks written in total
 
 	w io.Writer
-	b protocol.Buffer
-	z *zlib.Writer
+	z ZlibWriter
 }
 
 // NewCompressor for compressed w3g data
 func NewCompressor(w io.Writer) *Compressor {
-	z, _ := zlib.NewWriterLevelDict(nil, zlib.BestCompression, nil)
+	d, _ := NewCompressorWithOptions(w, defaultCompressorOptions)
+	return d
+}
+
+// NewCompressorWithOptions for compressed w3g data using a custom zlib level/dictionary/implementation
+func NewCompressorWithOptions(w io.Writer, opts CompressorOptions) (*Compressor, error) {
+	if opts.NewZlibWriter == nil {
+		opts.NewZlibWriter = defaultCompressorOptions.NewZlibWriter
+	}
+
+	z, err := opts.NewZlibWriter(nil, opts.Level, opts.Dict)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Compressor{
 		w: w,
 		z: z,
-	}
+	}, nil
 }
 
 // Write implements the io.Writer interface.
 func (d *Compressor) Write(b []byte) (int, error) {
 	var n = 0
+
+	var buf = scratchPool.Get()
+	defer scratchPool.Put(buf)
+
 	for len(b) > 0 {
 		var l = len(b)
 		if l > math.MaxUint16 {
@@ -46,12 +94,12 @@ func (d *Compressor) Write(b []byte) (int, error) {
 		}
 
 		// Header with placeholders for size
-		d.b.Truncate()
-		d.b.WriteUInt16(0)
-		d.b.WriteUInt16(uint16(l))
-		d.b.WriteUInt32(0)
+		buf.Truncate()
+		buf.WriteUInt16(0)
+		buf.WriteUInt16(uint16(l))
+		buf.WriteUInt32(0)
 
-		d.z.Reset(&d.b)
+		d.z.Reset(buf)
 		zn, err := d.z.Write(b[:l])
 		n += zn
 
@@ -63,15 +111,15 @@ func (d *Compressor) Write(b []byte) (int, error) {
 		}
 
 		// Update header
-		d.b.WriteUInt16At(0, uint16(d.b.Size()-8))
+		buf.WriteUInt16At(0, uint16(buf.Size()-8))
 
-		var crcHead = crc32.ChecksumIEEE(d.b.Bytes[:8])
-		d.b.WriteUInt16At(4, uint16(crcHead^crcHead>>16))
+		var crcHead = crc32.ChecksumIEEE(buf.Bytes[:8])
+		buf.WriteUInt16At(4, uint16(crcHead^crcHead>>16))
 
-		var crcData = crc32.ChecksumIEEE(d.b.Bytes[8:])
-		d.b.WriteUInt16At(6, uint16(crcData^crcData>>16))
+		var crcData = crc32.ChecksumIEEE(buf.Bytes[8:])
+		buf.WriteUInt16At(6, uint16(crcData^crcData>>16))
 
-		wn, err := d.w.Write(d.b.Bytes)
+		wn, err := d.w.Write(buf.Bytes)
 		d.SizeWritten += uint32(wn)
 		d.SizeTotal += uint32(zn)
 		d.NumBlocks++
@@ -93,9 +141,13 @@ type BufferedCompressor struct {
 	enc *RecordEncoder
 }
 
-// NewBufferedCompressorSize for compressed w3g with specified buffer size
-func NewBufferedCompressorSize(w io.Writer, size int, e Encoding) *BufferedCompressor {
-	var c = NewCompressor(w)
+// NewBufferedCompressorSizeWithOptions for compressed w3g with specified buffer size and zlib options
+func NewBufferedCompressorSizeWithOptions(w io.Writer, size int, e Encoding, opts CompressorOptions) (*BufferedCompressor, error) {
+	c, err := NewCompressorWithOptions(w, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	var b = bufio.NewWriterSize(c, size)
 	var r = NewRecordEncoder(e)
 
@@ -103,7 +155,13 @@ func NewBufferedCompressorSize(w io.Writer, size int, e Encoding) *BufferedCompr
 		Compressor: c,
 		Writer:     b,
 		enc:        r,
-	}
+	}, nil
+}
+
+// NewBufferedCompressorSize for compressed w3g with specified buffer size
+func NewBufferedCompressorSize(w io.Writer, size int, e Encoding) *BufferedCompressor {
+	d, _ := NewBufferedCompressorSizeWithOptions(w, size, e, defaultCompressorOptions)
+	return d
 }
 
 // NewBufferedCompressor for compressed w3g with default buffer size
@@ -0,0 +1,207 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"hash/crc32"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/nielsAD/gowarcraft3/protocol"
+)
+
+// pcBlock is one block dispatched to a compress worker. out is signaled by
+// the worker once buf (or err) is populated, so the writer goroutine can
+// wait on blocks in submission order regardless of which worker finishes
+// first.
+type pcBlock struct {
+	data []byte
+	out  chan *pcBlock
+
+	buf protocol.Buffer
+	zn  int
+	err error
+}
+
+// ParallelCompressor is an io.Writer that compresses data blocks using a pool
+// of worker goroutines, each holding its own zlib.Writer. Blocks are
+// compressed concurrently but merged back into submission order before being
+// written out, so the resulting stream is byte-identical to Compressor.
+type ParallelCompressor struct {
+	SizeWritten uint32 // Compressed size written in total
+	SizeTotal   uint32 // Decompressed size written in total
+	NumBlocks   uint32 // Blocks written in total
+
+	w    io.Writer
+	opts CompressorOptions
+
+	jobs  chan *pcBlock
+	order chan *pcBlock
+
+	workers sync.WaitGroup
+	writer  sync.WaitGroup
+
+	mtx  sync.Mutex
+	werr error
+}
+
+// NewParallelCompressor for compressed w3g data, splitting the stream into
+// ≤64KiB blocks that are compressed by a pool of workers goroutines
+func NewParallelCompressor(w io.Writer, workers int) *ParallelCompressor {
+	d, _ := NewParallelCompressorWithOptions(w, workers, defaultCompressorOptions)
+	return d
+}
+
+// NewParallelCompressorWithOptions for compressed w3g data using a custom
+// zlib level/dictionary/implementation, splitting the stream into ≤64KiB
+// blocks that are compressed by a pool of workers goroutines
+func NewParallelCompressorWithOptions(w io.Writer, workers int, opts CompressorOptions) (*ParallelCompressor, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	if opts.NewZlibWriter == nil {
+		opts.NewZlibWriter = defaultCompressorOptions.NewZlibWriter
+	}
+
+	if _, err := opts.NewZlibWriter(nil, opts.Level, opts.Dict); err != nil {
+		return nil, err
+	}
+
+	var d = &ParallelCompressor{
+		w:     w,
+		opts:  opts,
+		jobs:  make(chan *pcBlock, workers),
+		order: make(chan *pcBlock, workers*2),
+	}
+
+	d.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+
+	d.writer.Add(1)
+	go d.flush()
+
+	return d, nil
+}
+
+// work compresses blocks off d.jobs until it is closed
+func (d *ParallelCompressor) work() {
+	defer d.workers.Done()
+
+	z, err := d.opts.NewZlibWriter(nil, d.opts.Level, d.opts.Dict)
+	if err != nil {
+		d.fail(err)
+		for job := range d.jobs {
+			job.err = err
+			job.out <- job
+		}
+		return
+	}
+
+	for job := range d.jobs {
+		job.buf.Truncate()
+		job.buf.WriteUInt16(0)
+		job.buf.WriteUInt16(uint16(len(job.data)))
+		job.buf.WriteUInt32(0)
+
+		z.Reset(&job.buf)
+		zn, err := z.Write(job.data)
+		job.zn = zn
+
+		if err == nil {
+			err = z.Flush()
+		}
+		if err == nil {
+			job.buf.WriteUInt16At(0, uint16(job.buf.Size()-8))
+
+			var crcHead = crc32.ChecksumIEEE(job.buf.Bytes[:8])
+			job.buf.WriteUInt16At(4, uint16(crcHead^crcHead>>16))
+
+			var crcData = crc32.ChecksumIEEE(job.buf.Bytes[8:])
+			job.buf.WriteUInt16At(6, uint16(crcData^crcData>>16))
+		}
+
+		job.err = err
+		job.out <- job
+	}
+}
+
+// flush writes completed blocks to w in submission order
+func (d *ParallelCompressor) flush() {
+	defer d.writer.Done()
+
+	for job := range d.order {
+		var res = <-job.out
+		if res.err != nil {
+			d.fail(res.err)
+			continue
+		}
+
+		wn, err := d.w.Write(res.buf.Bytes)
+		d.SizeWritten += uint32(wn)
+		d.SizeTotal += uint32(res.zn)
+		d.NumBlocks++
+
+		if err != nil {
+			d.fail(err)
+		}
+	}
+}
+
+func (d *ParallelCompressor) fail(err error) {
+	d.mtx.Lock()
+	if d.werr == nil {
+		d.werr = err
+	}
+	d.mtx.Unlock()
+}
+
+func (d *ParallelCompressor) err() error {
+	d.mtx.Lock()
+	var err = d.werr
+	d.mtx.Unlock()
+	return err
+}
+
+// Write implements the io.Writer interface.
+func (d *ParallelCompressor) Write(b []byte) (int, error) {
+	var n = 0
+	for len(b) > 0 {
+		if err := d.err(); err != nil {
+			return n, err
+		}
+
+		var l = len(b)
+		if l > math.MaxUint16 {
+			l = math.MaxUint16
+		}
+
+		var job = &pcBlock{
+			data: append([]byte(nil), b[:l]...),
+			out:  make(chan *pcBlock, 1),
+		}
+
+		d.jobs <- job
+		d.order <- job
+
+		n += l
+		b = b[l:]
+	}
+
+	return n, nil
+}
+
+// Close drains all in-flight workers and flushes the remaining blocks
+func (d *ParallelCompressor) Close() error {
+	close(d.jobs)
+	d.workers.Wait()
+
+	close(d.order)
+	d.writer.Wait()
+
+	return d.werr
+}
@@ -0,0 +1,222 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package w3g
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// Errors
+var (
+	ErrBlockHeaderCRC = errors.New("w3g: Block header CRC mismatch")
+	ErrBlockDataCRC   = errors.New("w3g: Block data CRC mismatch")
+)
+
+// Decompressor is an io.Reader that decompresses data blocks written by Compressor
+type Decompressor struct {
+	SizeRead   uint32 // Decompressed size read in total
+	SizeTotal  uint32 // Decompressed size available in total
+	NumBlocks  uint32 // Blocks read in total
+	NumCorrupt uint32 // Corrupt blocks skipped in total
+
+	// Recover enables resynchronization on a corrupt block instead of
+	// returning the error to the caller. OnCorruptBlock, if set, is called
+	// for every block that fails CRC or zlib validation (whether or not
+	// Recover is enabled).
+	Recover        bool
+	OnCorruptBlock func(offset int64, err error)
+
+	// Dict is the preset zlib dictionary blocks were compressed with (see
+	// CompressorOptions.Dict). It must match the Compressor's dictionary
+	// exactly, or every block will fail to inflate.
+	Dict []byte
+
+	r      *bufio.Reader
+	offset int64
+	block  []byte
+	z      io.ReadCloser
+}
+
+// NewDecompressor for compressed w3g data
+func NewDecompressor(r io.Reader) *Decompressor {
+	return &Decompressor{r: bufio.NewReader(r)}
+}
+
+// Read implements the io.Reader interface.
+func (d *Decompressor) Read(p []byte) (int, error) {
+	var n = 0
+	for n < len(p) {
+		if len(d.block) == 0 {
+			if n > 0 {
+				return n, nil
+			}
+			if err := d.nextBlock(); err != nil {
+				return n, err
+			}
+		}
+
+		var c = copy(p[n:], d.block)
+		d.block = d.block[c:]
+		n += c
+		d.SizeRead += uint32(c)
+	}
+
+	return n, nil
+}
+
+// nextBlock reads (and, in Recover mode, resynchronizes past) the next block
+// into d.block
+func (d *Decompressor) nextBlock() error {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+			return err
+		}
+
+		var offset = d.offset
+		var compLen = binary.LittleEndian.Uint16(hdr[0:2])
+		var crcHead = binary.LittleEndian.Uint16(hdr[4:6])
+		var crcData = binary.LittleEndian.Uint16(hdr[6:8])
+
+		d.offset += int64(len(hdr)) + int64(compLen)
+
+		var data = make([]byte, compLen)
+		if _, err := io.ReadFull(d.r, data); err != nil {
+			return err
+		}
+
+		// crcHead is computed by the encoder over the full 8-byte header
+		// with the CRC fields still zeroed out, not just complen|declen
+		var zeroed [8]byte
+		copy(zeroed[:4], hdr[:4])
+
+		var calcHead = crc32.ChecksumIEEE(zeroed[:])
+		if uint16(calcHead^calcHead>>16) != crcHead {
+			if err := d.corrupt(offset, ErrBlockHeaderCRC); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var calcData = crc32.ChecksumIEEE(data)
+		if uint16(calcData^calcData>>16) != crcData {
+			if err := d.corrupt(offset, ErrBlockDataCRC); err != nil {
+				return err
+			}
+			continue
+		}
+
+		block, err := d.inflate(data)
+		if err != nil {
+			if err := d.corrupt(offset, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		d.block = block
+		d.SizeTotal += uint32(len(block))
+		d.NumBlocks++
+		return nil
+	}
+}
+
+// corrupt reports a bad block via OnCorruptBlock and decides whether
+// nextBlock should resynchronize (Recover) or abort with cause
+func (d *Decompressor) corrupt(offset int64, cause error) error {
+	d.NumCorrupt++
+	if d.OnCorruptBlock != nil {
+		d.OnCorruptBlock(offset, cause)
+	}
+	if !d.Recover {
+		return cause
+	}
+	return nil
+}
+
+// inflate decompresses data, reusing the zlib reader across blocks
+func (d *Decompressor) inflate(data []byte) ([]byte, error) {
+	if d.z == nil {
+		z, err := zlib.NewReaderDict(bytes.NewReader(data), d.Dict)
+		if err != nil {
+			return nil, err
+		}
+		d.z = z
+	} else if err := d.z.(zlib.Resetter).Reset(bytes.NewReader(data), d.Dict); err != nil {
+		return nil, err
+	}
+
+	// Compressor flushes each block without finalizing the deflate stream
+	// (zlib.Writer.Flush, not Close, so BFINAL is never set), so the reader
+	// always exhausts data at a valid sync-flush boundary rather than a
+	// proper stream end; that expected condition must not be treated as
+	// corruption.
+	var out, err = io.ReadAll(d.z)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return out, err
+}
+
+// BufferedDecompressor is an io.Reader that decompresses w3g data blocks
+type BufferedDecompressor struct {
+	*Decompressor
+	*bufio.Reader
+	dec *RecordDecoder
+}
+
+// NewBufferedDecompressorSize for decompressed w3g with specified buffer size
+func NewBufferedDecompressorSize(r io.Reader, size int, e Encoding) *BufferedDecompressor {
+	var d = NewDecompressor(r)
+	var b = bufio.NewReaderSize(d, size)
+	var c = NewRecordDecoder(e)
+
+	return &BufferedDecompressor{
+		Decompressor: d,
+		Reader:       b,
+		dec:          c,
+	}
+}
+
+// NewBufferedDecompressor for decompressed w3g with default buffer size
+func NewBufferedDecompressor(r io.Reader, e Encoding) *BufferedDecompressor {
+	return NewBufferedDecompressorSize(r, defaultBufSize, e)
+}
+
+// Read implements the io.Reader interface.
+func (d *BufferedDecompressor) Read(p []byte) (int, error) {
+	return d.Reader.Read(p)
+}
+
+// ReadRecord reads and deserializes the next record from d
+func (d *BufferedDecompressor) ReadRecord() (Record, int, error) {
+	return d.dec.Read(d.Reader)
+}
+
+// ReadRecords reads and deserializes records from d until EOF
+func (d *BufferedDecompressor) ReadRecords() ([]Record, int, error) {
+	var res []Record
+	var n = 0
+
+	for {
+		r, nn, err := d.ReadRecord()
+		n += nn
+
+		if err != nil {
+			if err == io.EOF {
+				return res, n, nil
+			}
+			return res, n, err
+		}
+
+		res = append(res, r)
+	}
+}
@@ -0,0 +1,124 @@
+package w3g_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/nielsAD/gowarcraft3/file/w3g"
+)
+
+// repeatingBlob generates compressible filler data, modeling the repetitive
+// game-state payloads replays actually carry (a wholly random block can
+// expand under zlib and overflow the 16-bit compressed-size field)
+func repeatingBlob(n int) []byte {
+	var b = make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func TestDecompressor(t *testing.T) {
+	var input = repeatingBlob(1 << 18)
+
+	var compressed bytes.Buffer
+	var c = w3g.NewCompressor(&compressed)
+	if _, err := c.Write(input); err != nil {
+		t.Fatal(err)
+	}
+
+	var d = w3g.NewDecompressor(&compressed)
+	var output, err = io.ReadAll(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(output, input) {
+		t.Fatal("decompressed output does not match input")
+	}
+	if d.NumBlocks != c.NumBlocks || d.SizeTotal != c.SizeTotal {
+		t.Fatal("decompressor counters do not match compressor counters")
+	}
+	if d.NumCorrupt != 0 {
+		t.Fatal("unexpected corrupt blocks")
+	}
+}
+
+func TestDecompressorRecover(t *testing.T) {
+	var input = repeatingBlob(3 * 64 * 1024)
+
+	var compressed bytes.Buffer
+	var c = w3g.NewCompressor(&compressed)
+	if _, err := c.Write(input); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt a byte in the payload of the second block
+	var corrupted = compressed.Bytes()
+	corrupted[10+len(corrupted[10:])/3] ^= 0xff
+
+	var corruptBlocks []int64
+	var d = w3g.NewDecompressor(bytes.NewReader(corrupted))
+	d.Recover = true
+	d.OnCorruptBlock = func(offset int64, err error) {
+		corruptBlocks = append(corruptBlocks, offset)
+	}
+
+	if _, err := io.ReadAll(d); err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptBlocks) == 0 {
+		t.Fatal("expected at least one corrupt block")
+	}
+	if d.NumCorrupt == 0 {
+		t.Fatal("expected NumCorrupt > 0")
+	}
+}
+
+func TestDecompressorAbortOnCorrupt(t *testing.T) {
+	var input = repeatingBlob(64 * 1024)
+
+	var compressed bytes.Buffer
+	var c = w3g.NewCompressor(&compressed)
+	if _, err := c.Write(input); err != nil {
+		t.Fatal(err)
+	}
+
+	var corrupted = compressed.Bytes()
+	corrupted[9] ^= 0xff
+
+	var d = w3g.NewDecompressor(bytes.NewReader(corrupted))
+	if _, err := io.ReadAll(d); err == nil {
+		t.Fatal("expected error without Recover")
+	}
+}
+
+func TestDecompressorDict(t *testing.T) {
+	var dict = repeatingBlob(1 << 10)
+	var input = repeatingBlob(1 << 18)
+
+	var compressed bytes.Buffer
+	var c, err = w3g.NewCompressorWithOptions(&compressed, w3g.CompressorOptions{
+		Level: zlib.BestCompression,
+		Dict:  dict,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Write(input); err != nil {
+		t.Fatal(err)
+	}
+
+	var d = w3g.NewDecompressor(&compressed)
+	d.Dict = dict
+
+	output, err := io.ReadAll(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, input) {
+		t.Fatal("decompressed output does not match input")
+	}
+}
@@ -0,0 +1,35 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+package util
+
+import "sync"
+
+// maxPooledCap bounds the capacity of buffers kept by BufferPool.Put, so a
+// single oversized packet does not pin a large allocation in the pool forever
+const maxPooledCap = 64 * 1024
+
+// BufferPool pools PacketBuffer instances for reuse across goroutines,
+// avoiding repeated allocations in hot packet-serialization paths
+type BufferPool struct {
+	pool sync.Pool
+}
+
+// Get returns a PacketBuffer from the pool, or a new empty one if the pool is empty
+func (p *BufferPool) Get() *PacketBuffer {
+	if v := p.pool.Get(); v != nil {
+		return v.(*PacketBuffer)
+	}
+	return &PacketBuffer{}
+}
+
+// Put resets b and returns it to the pool for reuse
+func (p *BufferPool) Put(b *PacketBuffer) {
+	if cap(b.Bytes) > maxPooledCap {
+		b.Bytes = nil
+	} else {
+		b.Bytes = b.Bytes[:0]
+	}
+	p.pool.Put(b)
+}
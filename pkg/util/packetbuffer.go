@@ -0,0 +1,467 @@
+// Author:  Niels A.D.
+// Project: gowarcraft3 (https://github.com/nielsAD/gowarcraft3)
+// License: Mozilla Public License, v2.0
+
+// Package util implements utility types and functions shared by other gowarcraft3 packages
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+)
+
+// Errors
+var (
+	ErrInvalidIP4               = errors.New("util: Invalid IPv4 address")
+	ErrInvalidSockAddr          = errors.New("util: Invalid sockaddr")
+	ErrNoCStringTerminatorFound = errors.New("util: No C-string terminator found")
+	ErrTruncatedVarint          = errors.New("util: Truncated varint")
+	ErrVarintOverflow           = errors.New("util: Varint overflows uint64")
+)
+
+// DWordString is a four-character string packed as a DWORD (e.g. record/version tags)
+type DWordString [4]byte
+
+// SockAddr represents a Windows sockaddr_in structure as used in BNet/w3g packets
+type SockAddr struct {
+	Port uint16
+	IP   net.IP
+}
+
+// Equal checks if s equals o
+func (s *SockAddr) Equal(o *SockAddr) bool {
+	return s.Port == o.Port && s.IP.Equal(o.IP)
+}
+
+// PacketBuffer is a byte buffer with helpers to read/write the primitives used
+// by Warcraft III / BNet wire protocols. Writes append to Bytes, reads consume
+// from its front, so a PacketBuffer doubles as a FIFO io.Reader/io.Writer.
+type PacketBuffer struct {
+	Bytes []byte
+}
+
+// Size returns the number of unread bytes in the buffer
+func (b *PacketBuffer) Size() int {
+	return len(b.Bytes)
+}
+
+// Truncate clears the buffer
+func (b *PacketBuffer) Truncate() {
+	b.Bytes = b.Bytes[:0]
+}
+
+// Skip discards the next n bytes
+func (b *PacketBuffer) Skip(n int) {
+	b.Bytes = b.Bytes[n:]
+}
+
+// Read implements the io.Reader interface
+func (b *PacketBuffer) Read(p []byte) (int, error) {
+	if len(b.Bytes) == 0 {
+		return 0, io.EOF
+	}
+
+	var n = copy(p, b.Bytes)
+	b.Bytes = b.Bytes[n:]
+	return n, nil
+}
+
+// Write implements the io.Writer interface
+func (b *PacketBuffer) Write(p []byte) (int, error) {
+	b.Bytes = append(b.Bytes, p...)
+	return len(p), nil
+}
+
+// minRead is the minimum number of free bytes grow guarantees before a Read call
+const minRead = 512
+
+// grow ensures at least n free bytes are available at the tail of Bytes
+func (b *PacketBuffer) grow(n int) {
+	if cap(b.Bytes)-len(b.Bytes) >= n {
+		return
+	}
+
+	var buf = make([]byte, len(b.Bytes), 2*cap(b.Bytes)+n)
+	copy(buf, b.Bytes)
+	b.Bytes = buf
+}
+
+// ReadFrom implements the io.ReaderFrom interface, reading directly into the
+// tail of Bytes so io.Copy does not need to stage through its own buffer
+func (b *PacketBuffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+
+	for {
+		b.grow(minRead)
+
+		var l = len(b.Bytes)
+		n, err := r.Read(b.Bytes[l:cap(b.Bytes)])
+		b.Bytes = b.Bytes[:l+n]
+		total += int64(n)
+
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return total, err
+		}
+	}
+}
+
+// WriteTo implements the io.WriterTo interface, draining Bytes directly into w
+func (b *PacketBuffer) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(b.Bytes)
+	b.Bytes = b.Bytes[n:]
+	return int64(n), err
+}
+
+// WriteUVarint appends v using the standard LEB128 variable-length encoding:
+// the low 7 bits of each byte hold data, with the MSB set while more bytes follow
+func (b *PacketBuffer) WriteUVarint(v uint64) {
+	for v >= 0x80 {
+		b.Bytes = append(b.Bytes, byte(v)|0x80)
+		v >>= 7
+	}
+	b.Bytes = append(b.Bytes, byte(v))
+}
+
+// uvarintLen returns the number of bytes WriteUVarint would emit for v
+func uvarintLen(v uint64) int {
+	var n = 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+// WriteUVarintAt overwrites the LEB128 encoding of v at offset, in place of a
+// previous varint occupying exactly width bytes. Unlike the other fixed-width
+// *At methods, a varint's encoded length depends on its value, so
+// WriteUVarintAt panics if v does not encode to exactly width bytes instead
+// of silently leaving stale bytes behind
+func (b *PacketBuffer) WriteUVarintAt(offset int, width int, v uint64) {
+	if uvarintLen(v) != width {
+		panic("util: WriteUVarintAt: v does not encode to width bytes")
+	}
+
+	for i := 0; i < width-1; i++ {
+		b.Bytes[offset] = byte(v) | 0x80
+		v >>= 7
+		offset++
+	}
+	b.Bytes[offset] = byte(v)
+}
+
+// WriteVarint appends v using zig-zag + LEB128 encoding
+func (b *PacketBuffer) WriteVarint(v int64) {
+	b.WriteUVarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+// WriteVarintAt overwrites the zig-zag LEB128 encoding of v at offset, in
+// place of a previous varint occupying exactly width bytes. See
+// WriteUVarintAt for the same-width requirement
+func (b *PacketBuffer) WriteVarintAt(offset int, width int, v int64) {
+	b.WriteUVarintAt(offset, width, uint64(v<<1)^uint64(v>>63))
+}
+
+// ReadUVarint reads and consumes a LEB128-encoded uint64, shift-accumulating
+// up to 10 bytes
+func (b *PacketBuffer) ReadUVarint() (uint64, error) {
+	var v uint64
+	var s uint
+
+	for i := 0; i < 10; i++ {
+		if len(b.Bytes) == 0 {
+			return 0, ErrTruncatedVarint
+		}
+
+		var c = b.Bytes[0]
+		b.Bytes = b.Bytes[1:]
+
+		if i == 9 && c >= 0x02 {
+			return 0, ErrVarintOverflow
+		}
+
+		v |= uint64(c&0x7f) << s
+		if c < 0x80 {
+			return v, nil
+		}
+		s += 7
+	}
+
+	return 0, ErrVarintOverflow
+}
+
+// ReadVarint reads and consumes a zig-zag + LEB128-encoded int64
+func (b *PacketBuffer) ReadVarint() (int64, error) {
+	var v, err = b.ReadUVarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+// WriteBlob appends p to the buffer as-is
+func (b *PacketBuffer) WriteBlob(p []byte) {
+	b.Bytes = append(b.Bytes, p...)
+}
+
+// WriteBlobAt overwrites len(p) bytes at offset
+func (b *PacketBuffer) WriteBlobAt(offset int, p []byte) {
+	copy(b.Bytes[offset:], p)
+}
+
+// ReadBlob reads and consumes the next n bytes
+func (b *PacketBuffer) ReadBlob(n int) []byte {
+	if n == 0 {
+		return nil
+	}
+
+	var res = b.Bytes[:n]
+	b.Bytes = b.Bytes[n:]
+	return res
+}
+
+// WriteUInt8 appends v to the buffer
+func (b *PacketBuffer) WriteUInt8(v uint8) {
+	b.Bytes = append(b.Bytes, v)
+}
+
+// WriteUInt8At overwrites the byte at offset with v
+func (b *PacketBuffer) WriteUInt8At(offset int, v uint8) {
+	b.Bytes[offset] = v
+}
+
+// ReadUInt8 reads and consumes the next byte
+func (b *PacketBuffer) ReadUInt8() uint8 {
+	var v = b.Bytes[0]
+	b.Bytes = b.Bytes[1:]
+	return v
+}
+
+// WriteUInt16 appends v to the buffer
+func (b *PacketBuffer) WriteUInt16(v uint16) {
+	b.Bytes = append(b.Bytes, 0, 0)
+	binary.LittleEndian.PutUint16(b.Bytes[len(b.Bytes)-2:], v)
+}
+
+// WriteUInt16At overwrites the 2 bytes at offset with v
+func (b *PacketBuffer) WriteUInt16At(offset int, v uint16) {
+	binary.LittleEndian.PutUint16(b.Bytes[offset:], v)
+}
+
+// ReadUInt16 reads and consumes the next 2 bytes
+func (b *PacketBuffer) ReadUInt16() uint16 {
+	var v = binary.LittleEndian.Uint16(b.Bytes)
+	b.Bytes = b.Bytes[2:]
+	return v
+}
+
+// WriteUInt32 appends v to the buffer
+func (b *PacketBuffer) WriteUInt32(v uint32) {
+	b.Bytes = append(b.Bytes, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b.Bytes[len(b.Bytes)-4:], v)
+}
+
+// WriteUInt32At overwrites the 4 bytes at offset with v
+func (b *PacketBuffer) WriteUInt32At(offset int, v uint32) {
+	binary.LittleEndian.PutUint32(b.Bytes[offset:], v)
+}
+
+// ReadUInt32 reads and consumes the next 4 bytes
+func (b *PacketBuffer) ReadUInt32() uint32 {
+	var v = binary.LittleEndian.Uint32(b.Bytes)
+	b.Bytes = b.Bytes[4:]
+	return v
+}
+
+// WriteBool appends v to the buffer
+func (b *PacketBuffer) WriteBool(v bool) {
+	if v {
+		b.WriteUInt8(1)
+	} else {
+		b.WriteUInt8(0)
+	}
+}
+
+// WriteBoolAt overwrites the byte at offset with v
+func (b *PacketBuffer) WriteBoolAt(offset int, v bool) {
+	if v {
+		b.WriteUInt8At(offset, 1)
+	} else {
+		b.WriteUInt8At(offset, 0)
+	}
+}
+
+// ReadBool reads and consumes the next byte
+func (b *PacketBuffer) ReadBool() bool {
+	return b.ReadUInt8() != 0
+}
+
+// WritePort appends v (network byte order) to the buffer
+func (b *PacketBuffer) WritePort(v uint16) {
+	b.Bytes = append(b.Bytes, 0, 0)
+	binary.BigEndian.PutUint16(b.Bytes[len(b.Bytes)-2:], v)
+}
+
+// WritePortAt overwrites the 2 bytes at offset with v (network byte order)
+func (b *PacketBuffer) WritePortAt(offset int, v uint16) {
+	binary.BigEndian.PutUint16(b.Bytes[offset:], v)
+}
+
+// ReadPort reads and consumes the next 2 bytes (network byte order)
+func (b *PacketBuffer) ReadPort() uint16 {
+	var v = binary.BigEndian.Uint16(b.Bytes)
+	b.Bytes = b.Bytes[2:]
+	return v
+}
+
+// WriteIP appends the 4-byte representation of ip to the buffer
+func (b *PacketBuffer) WriteIP(ip net.IP) error {
+	var ip4 = ip.To4()
+	if ip4 == nil {
+		return ErrInvalidIP4
+	}
+
+	b.Bytes = append(b.Bytes, ip4...)
+	return nil
+}
+
+// WriteIPAt overwrites the 4 bytes at offset with the representation of ip
+func (b *PacketBuffer) WriteIPAt(offset int, ip net.IP) error {
+	var ip4 = ip.To4()
+	if ip4 == nil {
+		return ErrInvalidIP4
+	}
+
+	copy(b.Bytes[offset:], ip4)
+	return nil
+}
+
+// ReadIP reads and consumes the next 4 bytes as a net.IP
+func (b *PacketBuffer) ReadIP() net.IP {
+	var ip = make(net.IP, 4)
+	copy(ip, b.Bytes[:4])
+	b.Bytes = b.Bytes[4:]
+	return ip
+}
+
+// WriteSockAddr appends the 16-byte sockaddr_in representation of s
+func (b *PacketBuffer) WriteSockAddr(s *SockAddr) error {
+	var family uint8
+	var ip4 = make([]byte, 4)
+
+	if s.IP != nil {
+		var v4 = s.IP.To4()
+		if v4 == nil {
+			return ErrInvalidIP4
+		}
+		family = 2
+		ip4 = v4
+	}
+
+	b.WriteUInt8(family)
+	b.WriteUInt8(0)
+	b.WritePort(s.Port)
+	b.Bytes = append(b.Bytes, ip4...)
+	b.Bytes = append(b.Bytes, make([]byte, 8)...)
+	return nil
+}
+
+// WriteSockAddrAt overwrites the 16 bytes at offset with the representation of s
+func (b *PacketBuffer) WriteSockAddrAt(offset int, s *SockAddr) error {
+	var family uint8
+	var ip4 = make([]byte, 4)
+
+	if s.IP != nil {
+		var v4 = s.IP.To4()
+		if v4 == nil {
+			return ErrInvalidIP4
+		}
+		family = 2
+		ip4 = v4
+	}
+
+	b.WriteUInt8At(offset, family)
+	b.WriteUInt8At(offset+1, 0)
+	b.WritePortAt(offset+2, s.Port)
+	copy(b.Bytes[offset+4:], ip4)
+	copy(b.Bytes[offset+8:], make([]byte, 8))
+	return nil
+}
+
+// ReadSockAddr reads and consumes the next 16 bytes as a SockAddr
+func (b *PacketBuffer) ReadSockAddr() (*SockAddr, error) {
+	var family = b.ReadUInt8()
+	if family != 0 && family != 2 {
+		return nil, ErrInvalidSockAddr
+	}
+
+	b.Skip(1)
+
+	var port = b.ReadPort()
+	var ip = b.ReadIP()
+	var zero = b.ReadBlob(8)
+
+	for _, z := range zero {
+		if z != 0 {
+			return nil, ErrInvalidSockAddr
+		}
+	}
+
+	if family == 0 {
+		if port != 0 || !ip.Equal(net.IPv4zero) {
+			return nil, ErrInvalidSockAddr
+		}
+		return &SockAddr{}, nil
+	}
+
+	return &SockAddr{Port: port, IP: ip}, nil
+}
+
+// WriteCString appends s to the buffer, followed by a null terminator
+func (b *PacketBuffer) WriteCString(s string) {
+	b.Bytes = append(b.Bytes, s...)
+	b.Bytes = append(b.Bytes, 0)
+}
+
+// WriteCStringAt overwrites len(s)+1 bytes at offset with s and a null terminator
+func (b *PacketBuffer) WriteCStringAt(offset int, s string) {
+	copy(b.Bytes[offset:], s)
+	b.Bytes[offset+len(s)] = 0
+}
+
+// ReadCString reads and consumes a null-terminated string
+func (b *PacketBuffer) ReadCString() (string, error) {
+	var i = bytes.IndexByte(b.Bytes, 0)
+	if i < 0 {
+		b.Truncate()
+		return "", ErrNoCStringTerminatorFound
+	}
+
+	var s = string(b.Bytes[:i])
+	b.Bytes = b.Bytes[i+1:]
+	return s, nil
+}
+
+// WriteDString appends v to the buffer
+func (b *PacketBuffer) WriteDString(v DWordString) {
+	b.Bytes = append(b.Bytes, v[:]...)
+}
+
+// WriteDStringAt overwrites the 4 bytes at offset with v
+func (b *PacketBuffer) WriteDStringAt(offset int, v DWordString) {
+	copy(b.Bytes[offset:], v[:])
+}
+
+// ReadDString reads and consumes the next 4 bytes as a DWordString
+func (b *PacketBuffer) ReadDString() DWordString {
+	var v DWordString
+	copy(v[:], b.Bytes[:4])
+	b.Bytes = b.Bytes[4:]
+	return v
+}
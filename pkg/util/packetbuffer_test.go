@@ -451,6 +451,99 @@ func TestDString(t *testing.T) {
 	}
 }
 
+func TestUVarint(t *testing.T) {
+	var vals = []uint64{0, 1, 127, 128, 300, 16384, 1 << 32, 1<<64 - 1}
+	var buf = util.PacketBuffer{Bytes: make([]byte, 0)}
+
+	for _, v := range vals {
+		buf.WriteUVarint(v)
+	}
+
+	for _, v := range vals {
+		read, err := buf.ReadUVarint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read != v {
+			t.Fatalf("read %v != %v", read, v)
+		}
+	}
+
+	if buf.Size() != 0 {
+		t.Fatalf("Leftover: %v != 0", buf.Size())
+	}
+
+	buf.WriteUVarint(300)
+	buf.WriteUVarintAt(0, 2, 200)
+	if read, err := buf.ReadUVarint(); err != nil || read != 200 {
+		t.Fatalf("WriteAt: %v, %v", read, err)
+	}
+
+	var overwritePanicked bool
+	func() {
+		defer func() {
+			overwritePanicked = recover() != nil
+		}()
+		buf.WriteUVarint(300)
+		buf.WriteUVarintAt(0, 2, 1)
+	}()
+	if !overwritePanicked {
+		t.Fatal("WriteUVarintAt: expected panic on width mismatch")
+	}
+	buf.ReadUVarint()
+
+	if _, err := (&util.PacketBuffer{Bytes: []byte{0x80, 0x80}}).ReadUVarint(); err != util.ErrTruncatedVarint {
+		t.Fatal("ErrTruncatedVarint expected")
+	}
+
+	var overflow = util.PacketBuffer{Bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}}
+	if _, err := overflow.ReadUVarint(); err != util.ErrVarintOverflow {
+		t.Fatal("ErrVarintOverflow expected")
+	}
+}
+
+func TestVarint(t *testing.T) {
+	var vals = []int64{0, 1, -1, 127, -127, 1 << 40, -(1 << 40), -1 << 63, 1<<63 - 1}
+	var buf = util.PacketBuffer{Bytes: make([]byte, 0)}
+
+	for _, v := range vals {
+		buf.WriteVarint(v)
+	}
+
+	for _, v := range vals {
+		read, err := buf.ReadVarint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read != v {
+			t.Fatalf("read %v != %v", read, v)
+		}
+	}
+
+	if buf.Size() != 0 {
+		t.Fatalf("Leftover: %v != 0", buf.Size())
+	}
+
+	buf.WriteVarint(-300)
+	buf.WriteVarintAt(0, 2, -200)
+	if read, err := buf.ReadVarint(); err != nil || read != -200 {
+		t.Fatalf("WriteAt: %v, %v", read, err)
+	}
+
+	var overwritePanicked bool
+	func() {
+		defer func() {
+			overwritePanicked = recover() != nil
+		}()
+		buf.WriteVarint(-300)
+		buf.WriteVarintAt(0, 2, -1)
+	}()
+	if !overwritePanicked {
+		t.Fatal("WriteVarintAt: expected panic on width mismatch")
+	}
+	buf.ReadVarint()
+}
+
 func BenchmarkWriteUInt32(b *testing.B) {
 	var buf = util.PacketBuffer{Bytes: make([]byte, 0)}
 
@@ -471,4 +564,60 @@ func BenchmarkReadUInt32(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		buf.ReadUInt32()
 	}
-}
\ No newline at end of file
+}
+
+// plainReader/plainWriter hide PacketBuffer's ReaderFrom/WriterTo so io.Copy
+// falls back to its generic staging-buffer path, giving BenchmarkIOCopyGeneric
+// a "before" baseline to compare BenchmarkIOCopy against
+type plainReader struct{ *util.PacketBuffer }
+type plainWriter struct{ *util.PacketBuffer }
+
+func (r plainReader) Read(p []byte) (int, error)  { return r.PacketBuffer.Read(p) }
+func (w plainWriter) Write(p []byte) (int, error) { return w.PacketBuffer.Write(p) }
+
+func BenchmarkIOCopyGeneric(b *testing.B) {
+	var src = make([]byte, 4096)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var r = util.PacketBuffer{Bytes: append([]byte(nil), src...)}
+		var w util.PacketBuffer
+		io.Copy(plainWriter{&w}, plainReader{&r})
+	}
+}
+
+func BenchmarkIOCopy(b *testing.B) {
+	var src = make([]byte, 4096)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var r = util.PacketBuffer{Bytes: append([]byte(nil), src...)}
+		var w util.PacketBuffer
+		io.Copy(&w, &r)
+	}
+}
+
+func BenchmarkPacketEncoding(b *testing.B) {
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var buf util.PacketBuffer
+		buf.WriteUInt32(1)
+		buf.WriteUInt32(2)
+		buf.WriteBlob(make([]byte, 32))
+	}
+}
+
+func BenchmarkPacketEncodingPooled(b *testing.B) {
+	var pool util.BufferPool
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var buf = pool.Get()
+		buf.WriteUInt32(1)
+		buf.WriteUInt32(2)
+		buf.WriteBlob(make([]byte, 32))
+		pool.Put(buf)
+	}
+}